@@ -0,0 +1,144 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/minio/minio/pkg/madmin"
+)
+
+// hri - heal result item, wraps a single madmin.HealResultItem with the
+// conveniences the heal display needs: drive state keyed by endpoint, and
+// whether the item is known to be recoverable outside of its local
+// erasure shards.
+type hri struct {
+	*madmin.HealResultItem
+
+	DriveInfo struct {
+		Before map[string]string
+		After  map[string]string
+	}
+
+	// HasReplica and HasRemoteTier mirror the server's view of whether
+	// this item has a resolved replication copy, or lives on a remote
+	// tier, regardless of the state of its local shards.
+	HasReplica    bool
+	HasRemoteTier bool
+}
+
+// newHRI - instantiate an hri from a single heal result item.
+func newHRI(i *madmin.HealResultItem) *hri {
+	h := &hri{HealResultItem: i}
+
+	h.DriveInfo.Before = make(map[string]string)
+	h.DriveInfo.After = make(map[string]string)
+	for _, d := range i.Before.Drives {
+		h.DriveInfo.Before[d.Endpoint] = d.State
+	}
+	for _, d := range i.After.Drives {
+		h.DriveInfo.After[d.Endpoint] = d.State
+	}
+
+	h.HasReplica = i.HasReplica
+	h.HasRemoteTier = i.HasRemoteTier
+
+	return h
+}
+
+// getHRTypeAndName - returns the item type and its bucket/object (or
+// bucket-only, for bucket-level items) name.
+func (h *hri) getHRTypeAndName() (typeStr, name string) {
+	typeStr = string(h.Type)
+	if h.Object == "" {
+		return typeStr, h.Bucket
+	}
+	return typeStr, h.Bucket + "/" + h.Object
+}
+
+// makeHealEntityString - human readable identifier for the item currently
+// being scanned, used in the "Scanned: " line of the heal UI.
+func (h *hri) makeHealEntityString() string {
+	_, name := h.getHRTypeAndName()
+	return fmt.Sprintf("%s: %s", h.Type, name)
+}
+
+// getHealResultStr - one-line, human readable summary of the heal result
+// for this item, used by printItemsQuietly.
+func (h *hri) getHealResultStr() string {
+	_, name := h.getHRTypeAndName()
+	if h.Detail != "" {
+		return fmt.Sprintf("%s (%s)", name, h.Detail)
+	}
+	return name
+}
+
+// getShardPlacementChange - the shard placement behind this item's health
+// color, before and after healing. This is the single source of truth
+// getObjectHCCChange and getReplicatedFileHCCChange build their
+// getHColCode calls from.
+func (h *hri) getShardPlacementChange() (before, after shardPlacement) {
+	beforeUp, afterUp := h.GetOnlineCounts()
+
+	before = shardPlacement{
+		DataShards:      h.DataBlocks,
+		ParityShards:    h.ParityBlocks,
+		AvailableShards: beforeUp,
+		HasReplica:      h.HasReplica,
+		HasRemoteTier:   h.HasRemoteTier,
+	}
+	after = before
+	after.AvailableShards = afterUp
+	return before, after
+}
+
+// getObjectHCCChange - health color code change (before -> after) for an
+// object heal result item.
+func (h *hri) getObjectHCCChange() (before, after hCol, err error) {
+	placementBefore, placementAfter := h.getShardPlacementChange()
+
+	before, err = getHColCode(placementBefore)
+	if err != nil {
+		return before, after, err
+	}
+	after, err = getHColCode(placementAfter)
+	if err != nil {
+		return before, after, err
+	}
+	return before, after, nil
+}
+
+// getReplicatedFileHCCChange - health color code change (before -> after)
+// for a metadata/bucket heal result item. The color is computed exactly
+// as in getObjectHCCChange, from the same shard placement (DataBlocks,
+// ParityBlocks and online drive counts): grey when a replica or remote
+// tier exists, otherwise by surplus ratio. There is no separate rule for
+// items without object-level erasure shards; for such an item with
+// DataBlocks below 1 and no replica or remote tier, an error is returned.
+func (h *hri) getReplicatedFileHCCChange() (before, after hCol, err error) {
+	placementBefore, placementAfter := h.getShardPlacementChange()
+
+	before, err = getHColCode(placementBefore)
+	if err != nil {
+		return before, after, err
+	}
+	after, err = getHColCode(placementAfter)
+	if err != nil {
+		return before, after, err
+	}
+	return before, after, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// healMetricsServer exposes the fields tracked in uiData as Prometheus
+// text-format metrics, refreshed on each poll of
+// DisplayAndFollowHealStatus, so long-running heals can be scraped and
+// alerted on rather than watched by eyeball.
+type healMetricsServer struct {
+	mu   sync.Mutex
+	text string
+}
+
+func newHealMetricsServer() *healMetricsServer {
+	return &healMetricsServer{}
+}
+
+func (m *healMetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	text := m.text
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(text))
+}
+
+// listenAndServe starts the metrics HTTP server in the background.
+// Listen failures are fatal, matching how other mc subcommands treat an
+// unusable listen address.
+func (m *healMetricsServer) listenAndServe(addr string) {
+	go func() {
+		err := http.ListenAndServe(addr, m)
+		fatalIf(probe.NewError(err), "Unable to start heal metrics listener on `"+addr+"`.")
+	}()
+}
+
+// refresh renders the current state of ui as Prometheus text-format
+// metrics and stores it for the next scrape.
+func (m *healMetricsServer) refresh(ui *uiData) {
+	var b strings.Builder
+
+	writeCounter := func(name, help string, v int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+
+	writeCounter("mc_heal_objects_scanned_total", "Total objects scanned by the current heal sequence.", ui.ObjectsScanned)
+	writeCounter("mc_heal_objects_healed_total", "Total objects healed by the current heal sequence.", ui.ObjectsHealed)
+	writeCounter("mc_heal_items_scanned_total", "Total items (objects, metadata, buckets) scanned.", ui.ItemsScanned)
+	writeCounter("mc_heal_items_healed_total", "Total items (objects, metadata, buckets) healed.", ui.ItemsHealed)
+	writeCounter("mc_heal_bytes_scanned_total", "Total bytes scanned by the current heal sequence.", ui.BytesScanned)
+
+	fmt.Fprintf(&b, "# HELP mc_heal_duration_seconds Time elapsed since the heal sequence started.\n# TYPE mc_heal_duration_seconds gauge\nmc_heal_duration_seconds %.0f\n",
+		ui.HealDuration.Seconds())
+
+	fmt.Fprintf(&b, "# HELP mc_heal_objects_by_health Objects observed at each health color.\n# TYPE mc_heal_objects_by_health gauge\n")
+	for _, col := range []hCol{hColGreen, hColYellow, hColRed, hColGrey} {
+		fmt.Fprintf(&b, "mc_heal_objects_by_health{color=%q} %d\n", strings.ToLower(string(col)), ui.HealthCols[col])
+	}
+
+	fmt.Fprintf(&b, "# HELP mc_heal_objects_by_online_drives Objects observed with a given count of online drives.\n# TYPE mc_heal_objects_by_online_drives gauge\n")
+	drives := make([]int, 0, len(ui.ObjectsByOnlineDrives))
+	for d := range ui.ObjectsByOnlineDrives {
+		drives = append(drives, d)
+	}
+	sort.Ints(drives)
+	for _, d := range drives {
+		fmt.Fprintf(&b, "mc_heal_objects_by_online_drives{drives=\"%d\"} %d\n", d, ui.ObjectsByOnlineDrives[d])
+	}
+
+	fmt.Fprintf(&b, "# HELP mc_heal_bg_disk_bytes_done Bytes healed so far on each background-healing disk.\n# TYPE mc_heal_bg_disk_bytes_done gauge\n")
+	for _, d := range ui.BgHealDisks {
+		fmt.Fprintf(&b, "mc_heal_bg_disk_bytes_done{endpoint=%q,pool=\"%d\",set=\"%d\"} %d\n",
+			d.Endpoint, d.PoolIndex, d.SetIndex, d.BytesDone)
+	}
+
+	fmt.Fprintf(&b, "# HELP mc_heal_mrf_objects_pending Objects currently queued for background healing (MRF).\n# TYPE mc_heal_mrf_objects_pending gauge\nmc_heal_mrf_objects_pending %d\n",
+		ui.MRF.ObjectsPending)
+
+	m.mu.Lock()
+	m.text = b.String()
+	m.mu.Unlock()
+}
@@ -0,0 +1,101 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/minio/mc/pkg/console"
+)
+
+// mrfStats holds the cluster-wide Most-Recently-Failed queue snapshot used
+// to report how far background healing is lagging behind newly failed
+// writes.
+type mrfStats struct {
+	ObjectsPending int64
+	BytesPending   int64
+	OldestAge      time.Duration
+}
+
+// fetchMRFStats polls the cluster-wide MRF metrics. Like background heal
+// status, a failure here is non-fatal: we simply keep the previous
+// snapshot so a transient error doesn't blank out the display.
+func (ui *uiData) fetchMRFStats() {
+	info, err := ui.Client.MRFStatus(context.Background())
+	if err != nil {
+		return
+	}
+	ui.MRF = mrfStats{
+		ObjectsPending: info.ObjectsPending,
+		BytesPending:   info.BytesPending,
+		OldestAge:      UTCNow().Sub(info.OldestItemTime),
+	}
+}
+
+// mrfETA estimates the time to drain the MRF queue from the rolling
+// ObjectsHealed/HealDuration throughput. Returns 0 when there isn't enough
+// history yet to estimate a rate.
+func (ui *uiData) mrfETA() time.Duration {
+	if ui.MRF.ObjectsPending <= 0 || ui.HealDuration <= 0 || ui.ObjectsHealed <= 0 {
+		return 0
+	}
+	rate := float64(ui.ObjectsHealed) / ui.HealDuration.Seconds()
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(ui.MRF.ObjectsPending)/rate) * time.Second
+}
+
+// printMRFStats prints a single "Pending in MRF" line summarizing queue
+// depth and estimated time-to-drain. Returns the number of lines printed,
+// so callers redrawing the display in place know how far to rewind.
+func (ui *uiData) printMRFStats() (lines int) {
+	if ui.MRF.ObjectsPending == 0 {
+		return 0
+	}
+	eta := ui.mrfETA()
+	etaStr := "unknown"
+	if eta > 0 {
+		etaStr = eta.Round(time.Second).String()
+	}
+	console.PrintC(fmt.Sprintf("  Pending in MRF: %s objects (%s), oldest queued %s ago, ETA %s\n",
+		humanize.Comma(ui.MRF.ObjectsPending), humanize.IBytes(uint64(ui.MRF.BytesPending)),
+		ui.MRF.OldestAge.Round(time.Second), etaStr))
+	return 1
+}
+
+// mrfJSON is the shape written into the "mrf" field of printStatsJSON and
+// printItemsJSON so log pipelines get MRF visibility without polling the
+// server directly.
+type mrfJSON struct {
+	ObjectsPending int64 `json:"objects_pending"`
+	BytesPending   int64 `json:"bytes_pending"`
+	OldestAgeSecs  int64 `json:"oldest_age_seconds"`
+	ETASeconds     int64 `json:"eta_seconds"`
+}
+
+func (ui *uiData) toMRFJSON() mrfJSON {
+	return mrfJSON{
+		ObjectsPending: ui.MRF.ObjectsPending,
+		BytesPending:   ui.MRF.BytesPending,
+		OldestAgeSecs:  int64(ui.MRF.OldestAge.Round(time.Second).Seconds()),
+		ETASeconds:     int64(ui.mrfETA().Round(time.Second).Seconds()),
+	}
+}
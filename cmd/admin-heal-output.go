@@ -0,0 +1,186 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/console"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/madmin"
+)
+
+// healOutputMode selects how heal progress is rendered, replacing the
+// old globalJSON/globalQuiet pair with a single, explicit choice.
+type healOutputMode string
+
+const (
+	healOutputTable  healOutputMode = "table"
+	healOutputJSON   healOutputMode = "json"
+	healOutputQuiet  healOutputMode = "quiet"
+	healOutputNDJSON healOutputMode = "ndjson"
+)
+
+// healOutputModeFromFlags resolves the --output flag, falling back to the
+// pre-existing global --json/--quiet flags so older invocations keep
+// working.
+func healOutputModeFromFlags(output string) healOutputMode {
+	switch healOutputMode(output) {
+	case healOutputTable, healOutputJSON, healOutputQuiet, healOutputNDJSON:
+		return healOutputMode(output)
+	}
+	switch {
+	case globalJSON:
+		return healOutputJSON
+	case globalQuiet:
+		return healOutputQuiet
+	default:
+		return healOutputTable
+	}
+}
+
+// ndjsonEnvelope is the stable, versioned envelope every NDJSON line is
+// wrapped in: {"api_version":"v1","event":"item|progress|summary|error","ts":...,"payload":{...}}.
+// This makes the heal stream consumable by log pipelines and dashboards
+// without polling the server directly.
+type ndjsonEnvelope struct {
+	APIVersion string      `json:"api_version"`
+	Event      string      `json:"event"`
+	TS         int64       `json:"ts"`
+	Payload    interface{} `json:"payload"`
+}
+
+const ndjsonAPIVersion = "v1"
+
+func printNDJSON(event string, payload interface{}) {
+	env := ndjsonEnvelope{
+		APIVersion: ndjsonAPIVersion,
+		Event:      event,
+		TS:         UTCNow().Unix(),
+		Payload:    payload,
+	}
+	b, err := json.Marshal(env)
+	fatalIf(probe.NewError(err), "Unable to marshal to JSON")
+	console.Println(string(b))
+}
+
+// ndjsonProgress is the payload of a "progress" event: the same rolling
+// counters used by the table and JSON outputs, emitted on every poll so
+// consumers get a heartbeat even when no items arrived in this batch.
+type ndjsonProgress struct {
+	ObjectsScanned        int64          `json:"objects_scanned"`
+	ObjectsHealed         int64          `json:"objects_healed"`
+	ItemsScanned          int64          `json:"items_scanned"`
+	ItemsHealed           int64          `json:"items_healed"`
+	BytesScanned          int64          `json:"bytes_scanned"`
+	HealDurationSecs      int64          `json:"heal_duration_seconds"`
+	HealthCols            map[hCol]int64 `json:"health_cols"`
+	ObjectsByOnlineDrives map[int]int64  `json:"objects_by_online_drives"`
+	MRF                   mrfJSON        `json:"mrf"`
+}
+
+func (ui *uiData) toNDJSONProgress() ndjsonProgress {
+	return ndjsonProgress{
+		ObjectsScanned:        ui.ObjectsScanned,
+		ObjectsHealed:         ui.ObjectsHealed,
+		ItemsScanned:          ui.ItemsScanned,
+		ItemsHealed:           ui.ItemsHealed,
+		BytesScanned:          ui.BytesScanned,
+		HealDurationSecs:      int64(ui.HealDuration.Round(time.Second).Seconds()),
+		HealthCols:            ui.HealthCols,
+		ObjectsByOnlineDrives: ui.ObjectsByOnlineDrives,
+		MRF:                   ui.toMRFJSON(),
+	}
+}
+
+// printItemsNDJSON emits one "item" event per healed item in this batch,
+// followed by a "progress" event carrying the cumulative counters. The
+// per-item payload carries the type, name, health, drives and size fields
+// of printItemsJSON's records, without their status, error and mrf fields.
+func (ui *uiData) printItemsNDJSON(s *madmin.HealTaskStatus) (err error) {
+	type change struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+	type healthChange struct {
+		change
+		ShardsBefore shardPlacement `json:"shards_before"`
+		ShardsAfter  shardPlacement `json:"shards_after"`
+	}
+	type itemPayload struct {
+		Type   string            `json:"type"`
+		Name   string            `json:"name"`
+		Health healthChange      `json:"health"`
+		Drives map[string]change `json:"drives"`
+		Size   int64             `json:"size"`
+	}
+
+	for _, item := range s.Items {
+		h := newHRI(&item)
+
+		var p itemPayload
+		p.Type, p.Name = h.getHRTypeAndName()
+		p.Drives = make(map[string]change)
+		p.Health.ShardsBefore, p.Health.ShardsAfter = h.getShardPlacementChange()
+
+		var b, a hCol
+		switch h.Type {
+		case madmin.HealItemMetadata, madmin.HealItemBucket:
+			b, a, err = h.getReplicatedFileHCCChange()
+		default:
+			if h.Type == madmin.HealItemObject {
+				p.Size = h.ObjectSize
+			}
+			b, a, err = h.getObjectHCCChange()
+		}
+		if err != nil {
+			return err
+		}
+		p.Health.Before = strings.ToLower(string(b))
+		p.Health.After = strings.ToLower(string(a))
+
+		for k := range h.DriveInfo.Before {
+			p.Drives[k] = change{h.DriveInfo.Before[k], h.DriveInfo.After[k]}
+		}
+
+		printNDJSON("item", p)
+	}
+
+	printNDJSON("progress", ui.toNDJSONProgress())
+	return nil
+}
+
+// printSummaryNDJSON emits the terminal "summary" event once the heal
+// sequence has finished or stopped.
+func (ui *uiData) printSummaryNDJSON(s *madmin.HealTaskStatus) {
+	type summaryPayload struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+		ndjsonProgress
+	}
+	p := summaryPayload{
+		Status:         "success",
+		ndjsonProgress: ui.toNDJSONProgress(),
+	}
+	if s.Summary == "stopped" {
+		p.Status = "error"
+		p.Error = s.FailureDetail
+	}
+	printNDJSON("summary", p)
+}
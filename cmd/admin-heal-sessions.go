@@ -0,0 +1,255 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/console"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/madmin"
+)
+
+// healSessionsDir returns ~/.mc/heal-sessions, creating it if needed.
+func healSessionsDir() (string, *probe.Error) {
+	dir := filepath.Join(mustGetMcConfigDir(), "heal-sessions")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", probe.NewError(err)
+	}
+	return dir, nil
+}
+
+// healSession is the on-disk representation of an in-progress `mc admin
+// heal` run. It carries everything UpdateDisplay needs to keep counting
+// seamlessly across a ctrl-C, a network blip, or a laptop going to sleep.
+type healSession struct {
+	ID          string          `json:"id"`
+	Alias       string          `json:"alias"`
+	Bucket      string          `json:"bucket"`
+	Prefix      string          `json:"prefix"`
+	ClientToken string          `json:"clientToken"`
+	HealOpts    madmin.HealOpts `json:"healOpts"`
+
+	// Cumulative counters, persisted so a resumed session's totals
+	// include work done before the interruption.
+	BytesScanned          int64          `json:"bytesScanned"`
+	ObjectsScanned        int64          `json:"objectsScanned"`
+	ItemsScanned          int64          `json:"itemsScanned"`
+	ObjectsHealed         int64          `json:"objectsHealed"`
+	ItemsHealed           int64          `json:"itemsHealed"`
+	ObjectsByOnlineDrives map[int]int64  `json:"objectsByOnlineDrives"`
+	HealthCols            map[hCol]int64 `json:"healthCols"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func newHealSessionID() string {
+	return fmt.Sprintf("%d", UTCNow().UnixNano())
+}
+
+func healSessionPath(id string) (string, *probe.Error) {
+	dir, err := healSessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// saveSession persists the current state of ui under its session id. Called on
+// every poll by DisplayAndFollowHealStatus so the sequence can be resumed
+// after an interruption.
+func (ui *uiData) saveSession() {
+	if ui.SessionID == "" {
+		return
+	}
+	path, err := healSessionPath(ui.SessionID)
+	if err != nil {
+		return
+	}
+	sess := healSession{
+		ID:                    ui.SessionID,
+		Alias:                 ui.Alias,
+		Bucket:                ui.Bucket,
+		Prefix:                ui.Prefix,
+		ClientToken:           ui.ClientToken,
+		HealOpts:              *ui.HealOpts,
+		BytesScanned:          ui.BytesScanned,
+		ObjectsScanned:        ui.ObjectsScanned,
+		ItemsScanned:          ui.ItemsScanned,
+		ObjectsHealed:         ui.ObjectsHealed,
+		ItemsHealed:           ui.ItemsHealed,
+		ObjectsByOnlineDrives: ui.ObjectsByOnlineDrives,
+		HealthCols:            ui.HealthCols,
+		UpdatedAt:             UTCNow(),
+	}
+	b, jerr := json.Marshal(sess)
+	if jerr != nil {
+		return
+	}
+	_ = ioutil.WriteFile(path, b, 0600)
+}
+
+// loadHealSession loads a previously persisted session by id.
+func loadHealSession(id string) (*healSession, *probe.Error) {
+	path, err := healSessionPath(id)
+	if err != nil {
+		return nil, err
+	}
+	b, rerr := ioutil.ReadFile(path)
+	if rerr != nil {
+		return nil, probe.NewError(rerr)
+	}
+	var sess healSession
+	if jerr := json.Unmarshal(b, &sess); jerr != nil {
+		return nil, probe.NewError(jerr)
+	}
+	return &sess, nil
+}
+
+// applyTo reloads the accumulated counters and client token from a
+// persisted session into ui, so the UI continues counting seamlessly
+// from where the previous run left off.
+func (sess *healSession) applyTo(ui *uiData) {
+	ui.SessionID = sess.ID
+	ui.Alias = sess.Alias
+	ui.Bucket = sess.Bucket
+	ui.Prefix = sess.Prefix
+	ui.ClientToken = sess.ClientToken
+	healOpts := sess.HealOpts
+	ui.HealOpts = &healOpts
+	ui.BytesScanned = sess.BytesScanned
+	ui.ObjectsScanned = sess.ObjectsScanned
+	ui.ItemsScanned = sess.ItemsScanned
+	ui.ObjectsHealed = sess.ObjectsHealed
+	ui.ItemsHealed = sess.ItemsHealed
+	ui.ObjectsByOnlineDrives = sess.ObjectsByOnlineDrives
+	if ui.ObjectsByOnlineDrives == nil {
+		ui.ObjectsByOnlineDrives = make(map[int]int64)
+	}
+	ui.HealthCols = sess.HealthCols
+	if ui.HealthCols == nil {
+		ui.HealthCols = make(map[hCol]int64)
+	}
+}
+
+// removeHealSession deletes a persisted session, e.g. once a heal sequence
+// has finished or been abandoned.
+func removeHealSession(id string) *probe.Error {
+	path, err := healSessionPath(id)
+	if err != nil {
+		return err
+	}
+	if rerr := os.Remove(path); rerr != nil && !os.IsNotExist(rerr) {
+		return probe.NewError(rerr)
+	}
+	return nil
+}
+
+// heartbeatInterval is both the heartbeat's own tick period and the
+// staleness threshold it applies to ui.lastPollAt().
+const heartbeatInterval = 10 * time.Second
+
+// heartbeat keeps polling the heal status on a fixed interval even when
+// the UI is in quiet mode, so the server doesn't drop the sequence for
+// lack of client activity. DisplayAndFollowHealStatus already polls on
+// every iteration of its own loop; heartbeat exists for the case where
+// that loop's own poll is blocked - e.g. a very slow scan, or a stalled
+// connection - for longer than the server's sequence timeout.
+//
+// The staleness check reads ui.lastPollAt(), which is updated atomically,
+// so heartbeat never blocks waiting on a lock the main loop's own
+// possibly-stalled Heal call is holding - it's precisely that stalled
+// case heartbeat has to be able to react to. Only the state mutation
+// that follows a response is serialized, via ui.poll's stateMu. render
+// is false: a quiet keep-alive poll shouldn't interleave console output
+// with the main loop's display.
+func (ui *uiData) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			last := ui.lastPollAt()
+			if !last.IsZero() && UTCNow().Sub(last) < heartbeatInterval {
+				continue
+			}
+			if _, err := ui.poll(false); err != nil {
+				return
+			}
+		}
+	}
+}
+
+var adminHealSessionsCmd = cli.Command{
+	Name:  "sessions",
+	Usage: "manage persisted heal sessions",
+	Subcommands: []cli.Command{
+		adminHealSessionsListCmd,
+		adminHealSessionsRmCmd,
+	},
+	HideHelpCommand: true,
+}
+
+var adminHealSessionsListCmd = cli.Command{
+	Name:   "list",
+	Usage:  "list persisted heal sessions",
+	Action: mainAdminHealSessionsList,
+}
+
+var adminHealSessionsRmCmd = cli.Command{
+	Name:   "rm",
+	Usage:  "remove a persisted heal session",
+	Action: mainAdminHealSessionsRm,
+}
+
+func mainAdminHealSessionsList(ctx *cli.Context) error {
+	dir, err := healSessionsDir()
+	fatalIf(err, "Unable to access heal sessions directory.")
+
+	entries, rerr := ioutil.ReadDir(dir)
+	fatalIf(probe.NewError(rerr), "Unable to list heal sessions.")
+
+	for _, entry := range entries {
+		id := entry.Name()[:len(entry.Name())-len(filepath.Ext(entry.Name()))]
+		sess, err := loadHealSession(id)
+		if err != nil {
+			continue
+		}
+		console.Println(fmt.Sprintf("%s\t%s/%s/%s\tupdated %s",
+			sess.ID, sess.Alias, sess.Bucket, sess.Prefix,
+			sess.UpdatedAt.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+func mainAdminHealSessionsRm(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "rm", 1)
+	}
+	err := removeHealSession(ctx.Args().Get(0))
+	fatalIf(err, "Unable to remove heal session.")
+	return nil
+}
@@ -17,10 +17,14 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	humanize "github.com/dustin/go-humanize"
@@ -59,34 +63,58 @@ func getHPrintCol(c hCol) *color.Color {
 	return nil
 }
 
-var (
-	hColOrder = []hCol{hColRed, hColYellow, hColGreen}
-	hColTable = map[int][]int{
-		1: {0, -1, 1},
-		2: {0, 1, 2},
-		3: {1, 2, 3},
-		4: {1, 2, 4},
-		5: {1, 3, 5},
-		6: {2, 4, 6},
-		7: {2, 4, 7},
-		8: {2, 5, 8},
-	}
-)
+// shardPlacement describes what it would take to fully reconstruct an
+// item from its local erasure shards, plus whether a copy is known to be
+// recoverable from elsewhere regardless of local shard loss.
+type shardPlacement struct {
+	DataShards      int `json:"data_shards"`
+	ParityShards    int `json:"parity_shards"`
+	AvailableShards int `json:"available_shards"`
+
+	// HasReplica is true when a site/bucket replication resolved copy
+	// of the item exists.
+	HasReplica bool `json:"has_replica"`
+	// HasRemoteTier is true when the item's body lives on a remote
+	// tier rather than local drives.
+	HasRemoteTier bool `json:"has_remote_tier"`
+}
 
-func getHColCode(surplusShards, parityShards int) (c hCol, err error) {
-	if parityShards < 1 || parityShards > 8 || surplusShards > parityShards {
-		return c, fmt.Errorf("Invalid parity shard count/surplus shard count given")
+// getHColCode computes a health color from a shard placement. The color
+// is grey whenever the item is fully recoverable from a replica or a
+// remote tier, regardless of local shard loss - that local loss is not
+// an emergency if another complete copy is already known about.
+// Otherwise, the color is driven by the ratio of surplus shards (shards
+// available beyond the minimum needed to reconstruct the data) to the
+// configured parity shards, bucketed at <= 1/3 (red), <= 2/3 (yellow)
+// and > 2/3 (green). This scales to any parity level, unlike the old
+// hard-coded 8-parity lookup table, and accounts for deployments that
+// mix erasure coding with replication and tiering.
+func getHColCode(p shardPlacement) (c hCol, err error) {
+	if p.HasReplica || p.HasRemoteTier {
+		return hColGrey, nil
 	}
-	if surplusShards < 0 {
-		return hColGrey, err
+
+	if p.DataShards < 1 || p.ParityShards < 0 {
+		return c, fmt.Errorf("Invalid data shard count/parity shard count given")
 	}
-	colRow := hColTable[parityShards]
-	for index, val := range colRow {
-		if val != -1 && surplusShards <= val {
-			return hColOrder[index], err
+
+	surplus := p.AvailableShards - p.DataShards
+	if p.ParityShards == 0 {
+		if surplus >= 0 {
+			return hColGreen, nil
 		}
+		return hColRed, nil
+	}
+
+	ratio := float64(surplus) / float64(p.ParityShards)
+	switch {
+	case ratio <= 1.0/3.0:
+		return hColRed, nil
+	case ratio <= 2.0/3.0:
+		return hColYellow, nil
+	default:
+		return hColGreen, nil
 	}
-	return c, fmt.Errorf("cannot get a heal color code")
 }
 
 type uiData struct {
@@ -97,6 +125,20 @@ type uiData struct {
 	HealOpts       *madmin.HealOpts
 	LastItem       *hri
 
+	// Alias and SessionID identify this run for the purposes of
+	// persisted, resumable heal sessions. SessionID is empty unless
+	// a session file is being maintained for this run.
+	Alias     string
+	SessionID string
+
+	// Metrics is non-nil when `--metrics-listen` was given, and is
+	// refreshed with the latest counters on every poll.
+	Metrics *healMetricsServer
+
+	// Output selects how heal progress is rendered. Defaults to
+	// healOutputTable when left zero-valued.
+	Output healOutputMode
+
 	// Total time since heal start
 	HealDuration time.Duration
 
@@ -120,6 +162,154 @@ type uiData struct {
 	// channel to receive a prompt string to indicate activity on
 	// the terminal
 	CurChan (<-chan string)
+
+	// Last fetched state of the server's background healing
+	// tracker, in the order the server returned it; printBgHealProgress
+	// sorts it by pool -> set -> drive. Populated by
+	// updateBgHealStats; nil until the first successful fetch.
+	BgHealDisks []madmin.HealingDisk
+
+	// Last fetched snapshot of the cluster-wide MRF queue. Populated
+	// by fetchMRFStats; zero value until the first successful fetch.
+	MRF mrfStats
+
+	// Raw shard placement behind the most recently processed item's
+	// health color, kept so operators can audit color decisions.
+	LastShardPlacement shardPlacement
+
+	// LastRenderLines is the number of lines updateUI printed on its
+	// most recent call. printBgHealProgress/printMRFStats print a
+	// variable number of extra lines depending on cluster activity, so
+	// DisplayAndFollowHealStatus reads this instead of assuming a fixed
+	// table height when rewinding the terminal between redraws.
+	LastRenderLines int
+
+	// stateMu guards the counter/session-persistence mutation that
+	// follows a successful poll, so the main DisplayAndFollowHealStatus
+	// loop and the heartbeat goroutine never mutate counters
+	// concurrently or tear the saved session file. It is deliberately
+	// not held across the network call itself - see poll.
+	stateMu sync.Mutex
+
+	// lastPollAtNano is the UnixNano of the last successful poll,
+	// accessed atomically. heartbeat checks it to decide whether it
+	// needs to poll itself, without contending with stateMu or
+	// blocking on whatever poll - main loop or its own - is currently
+	// in flight.
+	lastPollAtNano int64
+}
+
+// lastPollAt returns the time of the last successful poll, or the zero
+// Time if none has completed yet.
+func (ui *uiData) lastPollAt() time.Time {
+	nanos := atomic.LoadInt64(&ui.lastPollAtNano)
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// poll issues a single Heal status call and folds the result into ui's
+// counters. Shared by the main follow loop and heartbeat so both keep the
+// same counters and session file current. render controls whether the
+// result is also rendered to the console: the main loop renders,
+// heartbeat's quiet keep-alive polls do not.
+//
+// The network call itself runs without holding stateMu: it's the
+// long-running part, and heartbeat must be able to check staleness (via
+// the atomic lastPollAtNano) and issue its own keep-alive poll even while
+// the main loop's own call is still in flight. Only the state mutation
+// that follows a response is serialized.
+func (ui *uiData) poll(render bool) (res madmin.HealTaskStatus, err error) {
+	_, res, err = ui.Client.Heal(ui.Bucket, ui.Prefix, *ui.HealOpts,
+		ui.ClientToken, ui.ForceStart)
+	if err != nil {
+		return res, err
+	}
+	atomic.StoreInt64(&ui.lastPollAtNano, UTCNow().UnixNano())
+
+	ui.stateMu.Lock()
+	defer ui.stateMu.Unlock()
+
+	if render {
+		err = ui.UpdateDisplay(&res)
+	} else {
+		err = ui.updateState(&res)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	ui.saveSession()
+	return res, nil
+}
+
+// updateBgHealStats fetches the current state of the server's background
+// healing tracker. Failures are non-fatal: background heal status is a
+// best-effort addition to the foreground heal display, so we simply leave
+// BgHealDisks at its previous value if the call errors out.
+func (ui *uiData) updateBgHealStats() {
+	bgHeal, err := ui.Client.BackgroundHealStatus(context.Background())
+	if err != nil {
+		return
+	}
+	ui.BgHealDisks = bgHeal.HealDisks
+}
+
+// printBgHealProgress renders a table of background heal progress grouped by
+// pool -> set -> drive endpoint, with a percent-complete bar computed from
+// BytesDone/ObjectsTotalSize. This complements the per-object table in
+// updateUI by showing which disks are actively healing after a drive
+// replacement. Returns the number of lines printed, so callers redrawing
+// the display in place know how far to rewind.
+func (ui *uiData) printBgHealProgress() (lines int) {
+	if len(ui.BgHealDisks) == 0 {
+		return 0
+	}
+
+	type row struct {
+		pool, set int
+		disk      madmin.HealingDisk
+	}
+	rows := make([]row, len(ui.BgHealDisks))
+	for i, d := range ui.BgHealDisks {
+		rows[i] = row{pool: d.PoolIndex, set: d.SetIndex, disk: d}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].pool != rows[j].pool {
+			return rows[i].pool < rows[j].pool
+		}
+		if rows[i].set != rows[j].set {
+			return rows[i].set < rows[j].set
+		}
+		return rows[i].disk.DiskIndex < rows[j].disk.DiskIndex
+	})
+
+	console.PrintC("  Background heal progress:\n")
+	lines++
+	barLen := 12
+	barChar, emptyBarChar := "█", " "
+	for _, r := range rows {
+		d := r.disk
+		var pct float64
+		if d.ObjectsTotalSize > 0 {
+			pct = float64(d.BytesDone) * 100 / float64(d.ObjectsTotalSize)
+		}
+		filledLen := int(math.Ceil(float64(barLen) * pct / 100))
+		if filledLen > barLen {
+			filledLen = barLen
+		}
+		bar := strings.Repeat(barChar, filledLen) + strings.Repeat(emptyBarChar, barLen-filledLen)
+		console.PrintC(fmt.Sprintf("    pool=%d set=%d drive=%s  %5.1f%% %s  (%s/%s items, %s/%s, %s/%s failed)\n",
+			r.pool, r.set, d.Endpoint, pct, bar,
+			humanize.Comma(d.ItemsHealed), humanize.Comma(d.ObjectsTotalCount),
+			humanize.IBytes(uint64(d.BytesDone)), humanize.IBytes(uint64(d.ObjectsTotalSize)),
+			humanize.Comma(d.ItemsFailed), humanize.IBytes(uint64(d.BytesFailed))))
+		console.PrintC(fmt.Sprintf("      started %s, last update %s\n",
+			d.Started.Format(time.RFC3339), d.LastUpdate.Format(time.RFC3339)))
+		lines += 2
+	}
+	return lines
 }
 
 func (ui *uiData) updateStats(i madmin.HealResultItem) error {
@@ -160,6 +350,11 @@ func (ui *uiData) updateStats(i madmin.HealResultItem) error {
 	}
 
 	ui.HealthCols[afterCol]++
+
+	// Keep the raw shard placement behind the color around so operators
+	// can audit why the last item landed in its color bucket.
+	_, ui.LastShardPlacement = h.getShardPlacementChange()
+
 	return nil
 }
 
@@ -238,6 +433,9 @@ func (ui *uiData) printItemsQuietly(s *madmin.HealTaskStatus) (err error) {
 			return err
 		}
 		printColStr(b, a)
+		if _, after := h.getShardPlacementChange(); !after.HasReplica && !after.HasRemoteTier {
+			console.PrintC(fmt.Sprintf("(%d/%d shards) ", after.AvailableShards, after.DataShards+after.ParityShards))
+		}
 		hrStr := h.getHealResultStr()
 		switch h.Type {
 		case madmin.HealItemMetadata, madmin.HealItemBucketMetadata:
@@ -264,20 +462,28 @@ func (ui *uiData) printItemsJSON(s *madmin.HealTaskStatus) (err error) {
 		Before string `json:"before"`
 		After  string `json:"after"`
 	}
+	type healthChange struct {
+		change
+		ShardsBefore shardPlacement `json:"shards_before"`
+		ShardsAfter  shardPlacement `json:"shards_after"`
+	}
 	type healRec struct {
 		Status string            `json:"status"`
 		Error  string            `json:"error,omitempty"`
 		Type   string            `json:"type"`
 		Name   string            `json:"name"`
-		Health change            `json:"health"`
+		Health healthChange      `json:"health"`
 		Drives map[string]change `json:"drives"`
 		Size   int64             `json:"size"`
+		MRF    mrfJSON           `json:"mrf"`
 	}
 	makeHR := func(h *hri) (r healRec, err error) {
 		r.Status = "success"
 		r.Type, r.Name = h.getHRTypeAndName()
 		r.Drives = make(map[string]change)
 
+		r.Health.ShardsBefore, r.Health.ShardsAfter = h.getShardPlacementChange()
+
 		var b, a hCol
 		switch h.Type {
 		case madmin.HealItemMetadata, madmin.HealItemBucket:
@@ -298,6 +504,8 @@ func (ui *uiData) printItemsJSON(s *madmin.HealTaskStatus) (err error) {
 			r.Drives[k] = change{h.DriveInfo.Before[k], h.DriveInfo.After[k]}
 		}
 
+		r.MRF = ui.toMRFJSON()
+
 		return r, nil
 	}
 
@@ -316,15 +524,16 @@ func (ui *uiData) printItemsJSON(s *madmin.HealTaskStatus) (err error) {
 
 func (ui *uiData) printStatsJSON(s *madmin.HealTaskStatus) {
 	var summary struct {
-		Status         string `json:"status"`
-		Error          string `json:"error,omitempty"`
-		Type           string `json:"type"`
-		ObjectsScanned int64  `json:"objects_scanned"`
-		ObjectsHealed  int64  `json:"objects_healed"`
-		ItemsScanned   int64  `json:"items_scanned"`
-		ItemsHealed    int64  `json:"items_healed"`
-		Size           int64  `json:"size"`
-		ElapsedTime    int64  `json:"duration"`
+		Status         string  `json:"status"`
+		Error          string  `json:"error,omitempty"`
+		Type           string  `json:"type"`
+		ObjectsScanned int64   `json:"objects_scanned"`
+		ObjectsHealed  int64   `json:"objects_healed"`
+		ItemsScanned   int64   `json:"items_scanned"`
+		ItemsHealed    int64   `json:"items_healed"`
+		Size           int64   `json:"size"`
+		ElapsedTime    int64   `json:"duration"`
+		MRF            mrfJSON `json:"mrf"`
 	}
 
 	summary.Status = "success"
@@ -336,12 +545,19 @@ func (ui *uiData) printStatsJSON(s *madmin.HealTaskStatus) {
 	summary.ItemsHealed = ui.ItemsHealed
 	summary.Size = ui.BytesScanned
 	summary.ElapsedTime = int64(ui.HealDuration.Round(time.Second).Seconds())
+	summary.MRF = ui.toMRFJSON()
 
 	jBytes, err := json.Marshal(summary)
 	fatalIf(probe.NewError(err), "Unable to marshal to JSON")
 	console.Println(string(jBytes))
 }
 
+// baseRenderLines is the fixed height of updateUI's spinner/scanned/healed
+// lines plus the health-color table (1 header + len(dspOrder) rows). It
+// was updateUI's entire output before printBgHealProgress/printMRFStats
+// started appending a variable number of extra lines on top.
+const baseRenderLines = 8
+
 func (ui *uiData) updateUI(s *madmin.HealTaskStatus) (err error) {
 	itemCount := len(s.Items)
 	h := ui.LastItem
@@ -382,21 +598,52 @@ func (ui *uiData) updateUI(s *madmin.HealTaskStatus) (err error) {
 	}
 
 	t.DisplayTable(cellText)
+
+	bgLines := ui.printBgHealProgress()
+	mrfLines := ui.printMRFStats()
+
+	ui.LastRenderLines = baseRenderLines + bgLines + mrfLines
 	return nil
 }
 
-func (ui *uiData) UpdateDisplay(s *madmin.HealTaskStatus) (err error) {
-	// Update state
+// updateState folds a heal status response into ui's counters. It does no
+// rendering, so it's safe to call from a goroutine that must not print -
+// heartbeat uses this to keep counters and the persisted session current
+// without interleaving output with the main loop's display.
+//
+// It also refreshes BgHealDisks/MRF unconditionally, regardless of
+// ui.Output: printItemsJSON/printStatsJSON, the NDJSON progress/summary
+// payloads, and the Prometheus exporter all read those fields, and none
+// of them go through updateUI, the table renderer that used to be the
+// only caller of updateBgHealStats/fetchMRFStats.
+func (ui *uiData) updateState(s *madmin.HealTaskStatus) error {
 	ui.updateDuration(s)
 	for _, i := range s.Items {
-		ui.updateStats(i)
+		if err := ui.updateStats(i); err != nil {
+			return err
+		}
 	}
 
-	// Update display
-	switch {
-	case globalJSON:
+	ui.updateBgHealStats()
+	ui.fetchMRFStats()
+
+	if ui.Metrics != nil {
+		ui.Metrics.refresh(ui)
+	}
+	return nil
+}
+
+func (ui *uiData) UpdateDisplay(s *madmin.HealTaskStatus) (err error) {
+	if err = ui.updateState(s); err != nil {
+		return err
+	}
+
+	switch ui.Output {
+	case healOutputNDJSON:
+		err = ui.printItemsNDJSON(s)
+	case healOutputJSON:
 		err = ui.printItemsJSON(s)
-	case globalQuiet:
+	case healOutputQuiet:
 		err = ui.printItemsQuietly(s)
 	default:
 		err = ui.updateUI(s)
@@ -408,13 +655,7 @@ func (ui *uiData) DisplayAndFollowHealStatus() (err error) {
 	var res madmin.HealTaskStatus
 
 	for {
-		_, res, err = ui.Client.Heal(ui.Bucket, ui.Prefix, *ui.HealOpts,
-			ui.ClientToken, ui.ForceStart)
-		if err != nil {
-			return err
-		}
-
-		err = ui.UpdateDisplay(&res)
+		res, err = ui.poll(true)
 		if err != nil {
 			return err
 		}
@@ -429,15 +670,21 @@ func (ui *uiData) DisplayAndFollowHealStatus() (err error) {
 		}
 
 		time.Sleep(time.Second)
-		if !globalQuiet && !globalJSON {
-			console.RewindLines(8)
+		if ui.Output == healOutputTable || ui.Output == "" {
+			console.RewindLines(ui.LastRenderLines)
 		}
 	}
-	if globalJSON {
-		ui.printStatsJSON(&res)
-		return nil
+
+	if res.Summary == "finished" && ui.SessionID != "" {
+		_ = removeHealSession(ui.SessionID)
 	}
-	if globalQuiet {
+
+	switch ui.Output {
+	case healOutputNDJSON:
+		ui.printSummaryNDJSON(&res)
+	case healOutputJSON:
+		ui.printStatsJSON(&res)
+	case healOutputQuiet:
 		ui.printStatsQuietly(&res)
 	}
 	return nil
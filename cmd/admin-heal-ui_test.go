@@ -0,0 +1,102 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "testing"
+
+func TestGetHColCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       shardPlacement
+		want    hCol
+		wantErr bool
+	}{
+		{
+			name: "replica short-circuits to grey even with no local shards",
+			p:    shardPlacement{DataShards: 0, ParityShards: 0, AvailableShards: 0, HasReplica: true},
+			want: hColGrey,
+		},
+		{
+			name: "remote tier short-circuits to grey even with no local shards",
+			p:    shardPlacement{DataShards: 0, ParityShards: 0, AvailableShards: 0, HasRemoteTier: true},
+			want: hColGrey,
+		},
+		{
+			name:    "invalid data shard count errors",
+			p:       shardPlacement{DataShards: 0, ParityShards: 2, AvailableShards: 2},
+			wantErr: true,
+		},
+		{
+			name:    "invalid parity shard count errors",
+			p:       shardPlacement{DataShards: 4, ParityShards: -1, AvailableShards: 4},
+			wantErr: true,
+		},
+		{
+			name: "zero parity with non-negative surplus is green",
+			p:    shardPlacement{DataShards: 4, ParityShards: 0, AvailableShards: 4},
+			want: hColGreen,
+		},
+		{
+			name: "zero parity with negative surplus is red",
+			p:    shardPlacement{DataShards: 4, ParityShards: 0, AvailableShards: 3},
+			want: hColRed,
+		},
+		{
+			name: "no surplus at all is red",
+			p:    shardPlacement{DataShards: 4, ParityShards: 6, AvailableShards: 4},
+			want: hColRed,
+		},
+		{
+			name: "surplus ratio at the 1/3 boundary is red",
+			p:    shardPlacement{DataShards: 4, ParityShards: 6, AvailableShards: 6},
+			want: hColRed,
+		},
+		{
+			name: "surplus ratio between the 1/3 and 2/3 boundaries is yellow",
+			p:    shardPlacement{DataShards: 4, ParityShards: 6, AvailableShards: 7},
+			want: hColYellow,
+		},
+		{
+			name: "surplus ratio at the 2/3 boundary is yellow",
+			p:    shardPlacement{DataShards: 4, ParityShards: 6, AvailableShards: 8},
+			want: hColYellow,
+		},
+		{
+			name: "surplus ratio above 2/3 is green",
+			p:    shardPlacement{DataShards: 4, ParityShards: 6, AvailableShards: 9},
+			want: hColGreen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getHColCode(tt.p)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("getHColCode(%+v) = nil error, want an error", tt.p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getHColCode(%+v) returned unexpected error: %v", tt.p, err)
+			}
+			if got != tt.want {
+				t.Fatalf("getHColCode(%+v) = %s, want %s", tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Minio Client (C) 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/madmin"
+)
+
+var adminHealFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "scan",
+		Usage: "run a deep scan instead of the default normal scan",
+	},
+	cli.BoolFlag{
+		Name:  "recursive, r",
+		Usage: "heal recursively",
+	},
+	cli.BoolFlag{
+		Name:  "dry-run, n",
+		Usage: "only inspect data, but do not mutate",
+	},
+	cli.BoolFlag{
+		Name:  "force-start, f",
+		Usage: "force start a new heal sequence",
+	},
+	cli.BoolFlag{
+		Name:  "remove",
+		Usage: "remove dangling objects in heal sequence",
+	},
+	cli.StringFlag{
+		Name:  "resume",
+		Usage: "resume a heal sequence from a previously persisted session id",
+	},
+	cli.StringFlag{
+		Name:  "metrics-listen",
+		Usage: "expose heal progress as Prometheus metrics on the given address, e.g. :9099",
+	},
+	cli.StringFlag{
+		Name:  "output",
+		Usage: "set the output format: ndjson, json, table or quiet (defaults to table, or to --json/--quiet if given)",
+	},
+}
+
+var adminHealCmd = cli.Command{
+	Name:            "heal",
+	Usage:           "heal disks, buckets and objects",
+	Action:          mainAdminHeal,
+	OnUsageError:    onUsageError,
+	Before:          setGlobalsFromContext,
+	Flags:           append(adminHealFlags, globalFlags...),
+	Subcommands:     []cli.Command{adminHealSessionsCmd},
+	HideHelpCommand: true,
+}
+
+// mainAdminHeal - the entry point for admin heal command.
+func mainAdminHeal(ctx *cli.Context) error {
+	checkAdminHealSyntax(ctx)
+
+	args := ctx.Args()
+	aliasedURL := args.Get(0)
+
+	client, err := newAdminClient(aliasedURL)
+	fatalIf(err, "Unable to initialize admin connection.")
+
+	alias, bucket, prefix := splitHealAliasedURL(aliasedURL)
+
+	scanMode := madmin.HealNormalScan
+	if ctx.Bool("scan") {
+		scanMode = madmin.HealDeepScan
+	}
+
+	healOpts := madmin.HealOpts{
+		Recursive: ctx.Bool("recursive"),
+		DryRun:    ctx.Bool("dry-run"),
+		Remove:    ctx.Bool("remove"),
+		ScanMode:  scanMode,
+	}
+
+	ui := &uiData{
+		Alias:                 alias,
+		Bucket:                bucket,
+		Prefix:                prefix,
+		Client:                client,
+		ForceStart:            ctx.Bool("force-start"),
+		HealOpts:              &healOpts,
+		CurChan:               newSpinner(ctx),
+		Output:                healOutputModeFromFlags(ctx.String("output")),
+		ObjectsByOnlineDrives: make(map[int]int64),
+		HealthCols:            make(map[hCol]int64),
+	}
+
+	if sessionID := ctx.String("resume"); sessionID != "" {
+		sess, err := loadHealSession(sessionID)
+		fatalIf(err, "Unable to resume heal session `"+sessionID+"`.")
+		sess.applyTo(ui)
+	} else {
+		healStart, _, herr := client.Heal(bucket, prefix, healOpts, "", ui.ForceStart)
+		fatalIf(probe.NewError(herr), "Unable to start heal sequence.")
+		ui.ClientToken = healStart.ClientToken
+		ui.SessionID = newHealSessionID()
+	}
+
+	if addr := ctx.String("metrics-listen"); addr != "" {
+		ui.Metrics = newHealMetricsServer()
+		ui.Metrics.listenAndServe(addr)
+	}
+
+	heartbeatCtx, cancelHeartbeat := context.WithCancel(context.Background())
+	defer cancelHeartbeat()
+	go ui.heartbeat(heartbeatCtx)
+
+	e := ui.DisplayAndFollowHealStatus()
+	fatalIf(probe.NewError(e), "Unable to display heal status.")
+	return nil
+}
+
+func checkAdminHealSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "heal", 1)
+	}
+}
+
+// splitHealAliasedURL splits an "ALIAS/bucket/prefix" argument into its
+// alias, bucket and prefix parts. bucket and prefix are empty when not
+// given, in which case the heal sequence covers the whole alias.
+func splitHealAliasedURL(aliasedURL string) (alias, bucket, prefix string) {
+	parts := strings.SplitN(aliasedURL, "/", 3)
+	alias = parts[0]
+	if len(parts) > 1 {
+		bucket = parts[1]
+	}
+	if len(parts) > 2 {
+		prefix = parts[2]
+	}
+	return alias, bucket, prefix
+}